@@ -3,13 +3,17 @@ package govw
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
-	"log"
 	"net"
 	"os"
+	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -34,7 +38,45 @@ type VWDaemon struct {
 	Children int
 	Model    *VWModel
 	Test     bool
-	TCPQueue chan *net.TCPConn
+	TCPQueue chan *pooledConn
+
+	// Logger and Metrics are pluggable observability hooks. Leave them
+	// nil to use the stdlib-backed defaults.
+	Logger  Logger
+	Metrics Metrics
+
+	// GracePeriod is how long Stop waits for the vw process group to
+	// exit after SIGTERM before escalating to SIGKILL. Zero means 5s.
+	GracePeriod time.Duration
+
+	// procMu guards cmd, pid, and exited, which Run/superviseProcess
+	// write and Stop/Close/processAlive read from other goroutines.
+	procMu   sync.Mutex
+	cmd      *exec.Cmd
+	pid      int
+	exited   chan struct{}
+	stopping int32
+
+	// queueMu guards the TCPQueue field itself (not the channel's
+	// contents), which Run replaces on every restart while Predict,
+	// PredictBatch, and drainTCPQueue read it from other goroutines.
+	queueMu sync.RWMutex
+}
+
+// queue returns the current TCPQueue, synchronized against Run
+// replacing it on restart.
+func (vw *VWDaemon) queue() chan *pooledConn {
+	vw.queueMu.RLock()
+	defer vw.queueMu.RUnlock()
+
+	return vw.TCPQueue
+}
+
+// setQueue replaces TCPQueue, synchronized against concurrent readers.
+func (vw *VWDaemon) setQueue(q chan *pooledConn) {
+	vw.queueMu.Lock()
+	vw.TCPQueue = q
+	vw.queueMu.Unlock()
 }
 
 // Predict contain result of prediction
@@ -43,11 +85,28 @@ type Prediction struct {
 	Tag   string
 }
 
+// ParsePredictResult parses one line of VW daemon output — a
+// prediction value optionally followed by its tag, space-separated —
+// into a Prediction.
+func ParsePredictResult(res *string) *Prediction {
+	fields := strings.Fields(*res)
+
+	var prediction Prediction
+	if len(fields) > 0 {
+		prediction.Value, _ = strconv.ParseFloat(fields[0], 64)
+	}
+	if len(fields) > 1 {
+		prediction.Tag = fields[1]
+	}
+
+	return &prediction
+}
+
 // NewDaemon method return instanse of new Vowpal Wabbit daemon
-func NewDaemon(binPath string, port int, children int, modelPath string, test bool, updatable bool) *VWDaemon {
+func NewDaemon(binPath string, port int, children int, modelPath string, test bool, updatable bool) (*VWDaemon, error) {
 	info, err := os.Stat(modelPath)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("vw daemon: failed to stat model file: %w", err)
 	}
 
 	daemon := &VWDaemon{
@@ -58,177 +117,399 @@ func NewDaemon(binPath string, port int, children int, modelPath string, test bo
 		Test:     test,
 	}
 
-	if updatable {
-		go modelFileChecker(daemon)
+	return daemon, nil
+}
+
+// prefixLogWriter forwards a child process's output to a Logger,
+// tagging each line so multiple daemons can be told apart.
+type prefixLogWriter struct {
+	prefix string
+	logger Logger
+}
+
+func (w prefixLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line != "" {
+			w.logger.Infof("%s: %s", w.prefix, line)
+		}
 	}
 
-	return daemon
+	return len(p), nil
 }
 
-func (vw *VWDaemon) getTCPConn() *net.TCPConn {
+// pooledConn pairs a TCP connection with its bufio.Reader, so a reader
+// isn't reallocated (discarding any bytes already buffered from a prior
+// response) on every call.
+type pooledConn struct {
+	*net.TCPConn
+	*bufio.Reader
+}
+
+func (vw *VWDaemon) getTCPConn() (*pooledConn, error) {
 	tcpAddr, err := net.ResolveTCPAddr("tcp", fmt.Sprintf(":%d", vw.Port))
 	if err != nil {
-		log.Fatal("Error while resolving IP addr: ", err)
+		return nil, fmt.Errorf("vw daemon: failed to resolve IP addr: %w", err)
 	}
 
 	conn, err := net.DialTCP("tcp", nil, tcpAddr)
 	if err != nil {
-		log.Fatal("Error while dialing TCP", err)
+		return nil, fmt.Errorf("vw daemon: failed to dial TCP: %w", err)
 	}
 
-	return conn
+	return &pooledConn{conn, bufio.NewReader(conn)}, nil
 }
 
-func (vw *VWDaemon) makeTCPConnQueue() {
+// replaceConn dials a fresh connection to make up for one discarded
+// after an I/O error, so the pool doesn't shrink over time.
+func (vw *VWDaemon) replaceConn() {
+	pc, err := vw.getTCPConn()
+	if err != nil {
+		vw.logger().Warnf("vw daemon: failed to replace broken connection: %v", err)
+		return
+	}
+
+	vw.queue() <- pc
+}
+
+func (vw *VWDaemon) makeTCPConnQueue() error {
 	size := vw.Children / 2
+	queue := vw.queue()
 
 	for i := 0; i < size; i++ {
-		vw.TCPQueue <- vw.getTCPConn()
+		pc, err := vw.getTCPConn()
+		if err != nil {
+			return fmt.Errorf("vw daemon: failed to create TCP connection queue: %w", err)
+		}
+
+		queue <- pc
 	}
 
-	log.Println("Queue of TCP connections for VW is created:", size)
+	vw.metrics().SetTCPPoolSize(size)
+	vw.logger().Infof("Queue of TCP connections for VW is created: %d", size)
+
+	return nil
 }
 
-// Run method send command for starting new VW daemon.
+// Run method starts a new VW daemon as a supervised child process.
 func (vw *VWDaemon) Run() error {
 	if vw.IsExist(3, 100) {
 		vw.Stop()
 	}
 
-	cmd := fmt.Sprintf("vw --daemon --threads --quiet --port %d --num_children %d", vw.Port, vw.Children)
+	args := []string{"--daemon", "--threads", "--quiet", "--port", strconv.Itoa(vw.Port), "--num_children", strconv.Itoa(vw.Children)}
 
 	if vw.Model.Path != "" {
-		cmd += fmt.Sprintf(" -i  %s", vw.Model.Path)
+		args = append(args, "-i", vw.Model.Path)
 	}
 
 	if vw.Test {
-		cmd += " -t"
+		args = append(args, "-t")
 	}
 
-	if _, err := runCommand(cmd, true); err != nil {
-		panic(err)
+	cmd := exec.Command(vw.BinPath, args...)
+	cmd.Stdout = prefixLogWriter{fmt.Sprintf("vw[%d][stdout]", vw.Port), vw.logger()}
+	cmd.Stderr = prefixLogWriter{fmt.Sprintf("vw[%d][stderr]", vw.Port), vw.logger()}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("vw daemon: failed to start: %w", err)
 	}
 
+	exited := make(chan struct{})
+
+	vw.procMu.Lock()
+	vw.cmd = cmd
+	vw.pid = cmd.Process.Pid
+	vw.exited = exited
+	vw.procMu.Unlock()
+
+	go vw.superviseProcess(cmd, exited)
+
 	if !vw.IsExist(5, 500) {
-		log.Fatal("Failed to start daemon!")
+		return fmt.Errorf("vw daemon: failed to start on port %d", vw.Port)
 	}
 
-	log.Printf("Vowpal wabbit daemon is running on port: %d", vw.Port)
+	vw.logger().Infof("Vowpal wabbit daemon is running on port: %d", vw.Port)
 
-	vw.TCPQueue = make(chan *net.TCPConn, vw.Children/2)
-	vw.makeTCPConnQueue()
+	vw.setQueue(make(chan *pooledConn, vw.Children/2))
+	if err := vw.makeTCPConnQueue(); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-// Stop current daemon
+// superviseProcess waits for the vw child to exit and restarts it
+// automatically, unless the exit was requested by Stop.
+func (vw *VWDaemon) superviseProcess(cmd *exec.Cmd, exited chan struct{}) {
+	err := cmd.Wait()
+
+	// Read stopping before closing exited: Stop resets it as soon as it
+	// sees exited closed, so reading it afterwards could miss the stop.
+	stopping := atomic.LoadInt32(&vw.stopping) == 1
+	close(exited)
+
+	if stopping {
+		return
+	}
+
+	vw.logger().Warnf("vw daemon on port %d exited unexpectedly: %v; restarting", vw.Port, err)
+	vw.metrics().IncDaemonRestarts()
+
+	if err := vw.Run(); err != nil {
+		vw.logger().Errorf("vw daemon on port %d failed to restart: %v", vw.Port, err)
+	}
+}
+
+// Stop sends SIGTERM to the vw process group and waits for it to exit,
+// escalating to SIGKILL if it doesn't stop within a few seconds.
 func (vw *VWDaemon) Stop() error {
-	cmd := fmt.Sprintf("pkill -9 -f \"vw.*--port %d\"", vw.Port)
-	if _, err := runCommand(cmd, true); err != nil {
-		panic(err)
+	vw.procMu.Lock()
+	cmd, pid, exited := vw.cmd, vw.pid, vw.exited
+	vw.procMu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return nil
+	}
+
+	// Already stopped: the pid has been reaped, so there is nothing to
+	// signal and a repeated Stop (or Close) is a no-op.
+	select {
+	case <-exited:
+		return nil
+	default:
+	}
+
+	atomic.StoreInt32(&vw.stopping, 1)
+	defer atomic.StoreInt32(&vw.stopping, 0)
+
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return fmt.Errorf("vw daemon: failed to resolve process group: %w", err)
+	}
+
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("vw daemon: failed to signal process group: %w", err)
+	}
+
+	gracePeriod := vw.GracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = 5 * time.Second
 	}
 
-	if vw.IsExist(5, 500) {
-		log.Fatal("Failed to stop daemon!")
+	select {
+	case <-exited:
+	case <-time.After(gracePeriod):
+		syscall.Kill(-pgid, syscall.SIGKILL)
+		<-exited
 	}
 
 	return nil
 }
 
 // Predict method get slice of bytes (you should convert your predict string to bytes),
-// then send data to VW daemon for getting prediction result.
+// then send data to VW daemon for getting prediction result. It is
+// equivalent to PredictContext with a context that never cancels.
 func (vw *VWDaemon) Predict(pData []byte) (*Prediction, error) {
+	return vw.PredictContext(context.Background(), pData)
+}
+
+// PredictContext behaves like Predict, but honors ctx while waiting for
+// a pooled connection and derives read/write deadlines from ctx's
+// deadline, if any. On I/O error the broken connection is discarded and
+// replaced rather than leaving the pool permanently short.
+func (vw *VWDaemon) PredictContext(ctx context.Context, pData []byte) (*Prediction, error) {
+	start := time.Now()
+	vw.metrics().IncPredictTotal()
+
+	prediction, err := vw.predictContext(ctx, pData)
+
+	vw.metrics().ObservePredictLatency(time.Since(start).Seconds())
+	if err != nil {
+		vw.metrics().IncPredictErrors()
+	}
+
+	return prediction, err
+}
+
+func (vw *VWDaemon) predictContext(ctx context.Context, pData []byte) (*Prediction, error) {
 	// Check if we have `\n` symbol in the end of prediction string
 	if pData[len(pData)-1] != endOfLine {
 		pData = append(pData, endOfLine)
 	}
 
-	conn := <-vw.TCPQueue
+	var pc *pooledConn
+	select {
+	case pc = <-vw.queue():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	deadline, _ := ctx.Deadline()
+	pc.SetWriteDeadline(deadline)
+	pc.SetReadDeadline(deadline)
 
-	_, err := conn.Write(pData)
-	if err != nil {
-		log.Fatal("Error via writing to VW TCP connections: ", err)
+	if _, err := pc.Write(pData); err != nil {
+		pc.Close()
+		vw.replaceConn()
+		return nil, fmt.Errorf("vw daemon: failed to write prediction request: %w", err)
 	}
 
-	res, err := bufio.NewReader(conn).ReadString('\n')
+	res, err := pc.ReadString('\n')
 	if err != nil {
-		log.Fatal("Error via reading VW response: ", err)
+		pc.Close()
+		vw.replaceConn()
+		return nil, fmt.Errorf("vw daemon: failed to read prediction response: %w", err)
 	}
 
-	vw.TCPQueue <- conn
+	vw.queue() <- pc
 
 	return ParsePredictResult(&res), nil
 }
 
-func (vw *VWDaemon) WorkersCount() (int, error) {
-	cmd := fmt.Sprintf("pgrep -f 'vw.*--port %d' | wc -l", vw.Port)
-	res, err := runCommand(cmd, false)
-	if err != nil {
-		return 0, err
+// PredictBatch writes a batch of newline-separated examples to VW over
+// a single pooled connection, then reads back exactly one prediction
+// line per example. The VW daemon protocol accepts many examples per
+// connection, so batching amortizes TCP round-trip and scheduler
+// overhead across the whole batch instead of paying it per example.
+func (vw *VWDaemon) PredictBatch(examples [][]byte) ([]*Prediction, error) {
+	if len(examples) == 0 {
+		return nil, nil
 	}
-	count, err := strconv.Atoi(strings.Trim(string(res), "\n"))
-	if err != nil {
-		return 0, err
+
+	var buf bytes.Buffer
+	for _, example := range examples {
+		buf.Write(example)
+		if len(example) == 0 || example[len(example)-1] != endOfLine {
+			buf.WriteByte(endOfLine)
+		}
+	}
+	buf.WriteByte(endOfLine)
+
+	pc := <-vw.queue()
+
+	// Deadlines persist on a net.Conn until explicitly changed, so a
+	// pooled conn previously used by a PredictContext call with a
+	// deadline needs it cleared here, or it can fail immediately with
+	// an unrelated timeout.
+	pc.SetWriteDeadline(time.Time{})
+	pc.SetReadDeadline(time.Time{})
+
+	if _, err := pc.Write(buf.Bytes()); err != nil {
+		pc.Close()
+		vw.replaceConn()
+		return nil, fmt.Errorf("vw daemon: failed to write prediction batch: %w", err)
+	}
+
+	predictions := make([]*Prediction, len(examples))
+	for i := range examples {
+		res, err := pc.ReadString('\n')
+		if err != nil {
+			pc.Close()
+			vw.replaceConn()
+			return nil, fmt.Errorf("vw daemon: failed to read prediction batch response: %w", err)
+		}
+
+		predictions[i] = ParsePredictResult(&res)
+	}
+
+	vw.queue() <- pc
+
+	return predictions, nil
+}
+
+// predictStreamBatchSize caps how many examples PredictStream accumulates
+// before flushing them to PredictBatch.
+const predictStreamBatchSize = 32
+
+// PredictStream predicts examples received on in, batching them through
+// PredictBatch (up to predictStreamBatchSize at a time) so pipeline
+// callers get the same amortized per-connection overhead PredictBatch
+// gives a caller that already has its examples in hand. Predictions are
+// sent to out in order. It runs until in is closed, and logs (rather
+// than stops on) batch errors.
+func (vw *VWDaemon) PredictStream(in <-chan []byte, out chan<- *Prediction) {
+	batch := make([][]byte, 0, predictStreamBatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		predictions, err := vw.PredictBatch(batch)
+		if err != nil {
+			vw.logger().Warnf("vw daemon: predict stream batch error: %v", err)
+		} else {
+			for _, prediction := range predictions {
+				out <- prediction
+			}
+		}
+
+		batch = batch[:0]
+	}
+
+	for pData := range in {
+		batch = append(batch, pData)
+		if len(batch) >= predictStreamBatchSize {
+			flush()
+		}
 	}
 
-	// We should substract 1 from count, to get clear result without
-	// side effect of using `sh -c` command in `exec.Command`.
-	return count - 1, nil
+	flush()
 }
 
-// IsExist method checks if VW daemon and all of his childrens is running.
+// IsExist method checks if the tracked VW process is still alive.
 // You shoud defain count of tries and delay in milliseconds between each try.
 func (vw *VWDaemon) IsExist(tries int, delay int) bool {
-	var count int
-	var err error
-
-	log.Println("Start checking IsExist!")
+	vw.logger().Debugf("Start checking IsExist!")
 	for i := 0; i < tries; i++ {
-		count, err = vw.WorkersCount()
-
-		// We add 1 to `vw.children`, because we still have the parent process.
-		if count == vw.Children+1 {
+		if vw.processAlive() {
 			return true
 		}
 
 		time.Sleep(time.Millisecond * time.Duration(delay))
 	}
-	if err != nil {
-		log.Fatal("Can't getting VW workers count.", err)
-	}
 
 	return false
 }
 
-func (vw *VWDaemon) DeepCopy() *VWDaemon {
+// processAlive reports whether the tracked vw pid is still running, by
+// sending it the null signal.
+func (vw *VWDaemon) processAlive() bool {
+	vw.procMu.Lock()
+	cmd, pid := vw.cmd, vw.pid
+	vw.procMu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return false
+	}
+
+	return syscall.Kill(pid, 0) == nil
+}
+
+func (vw *VWDaemon) DeepCopy() (*VWDaemon, error) {
 	var copyBuffer bytes.Buffer
 	var newVW VWDaemon
 
 	enc := gob.NewEncoder(&copyBuffer)
-	err := enc.Encode(vw)
-	if err != nil {
-		log.Fatal("Deep copy encode:", err)
+	if err := enc.Encode(vw); err != nil {
+		return nil, fmt.Errorf("vw daemon: deep copy encode: %w", err)
 	}
 
 	dec := gob.NewDecoder(&copyBuffer)
-	err = dec.Decode(&newVW)
-	if err != nil {
-		log.Fatal("Deep copy decode:", err)
+	if err := dec.Decode(&newVW); err != nil {
+		return nil, fmt.Errorf("vw daemon: deep copy decode: %w", err)
 	}
 
-	return &newVW
+	return &newVW, nil
 }
 
 // IsChanged method checks whether the model file has been modified.
-func (model *VWModel) IsChanged() bool {
+func (model *VWModel) IsChanged() (bool, error) {
 	info, err := os.Stat(model.Path)
 	if err != nil {
-		panic(err)
+		return false, fmt.Errorf("vw daemon: failed to stat model file: %w", err)
 	}
 
-	if model.ModTime != info.ModTime() {
-		return true
-	}
-
-	return false
+	return model.ModTime != info.ModTime(), nil
 }
@@ -0,0 +1,63 @@
+package govw
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestParsePredictResult(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Prediction
+	}{
+		{"0.5 example1\n", Prediction{Value: 0.5, Tag: "example1"}},
+		{"1\n", Prediction{Value: 1, Tag: ""}},
+		{"-0.25 tag_with_underscore", Prediction{Value: -0.25, Tag: "tag_with_underscore"}},
+	}
+
+	for _, c := range cases {
+		in := c.in
+		got := ParsePredictResult(&in)
+		if got.Value != c.want.Value || got.Tag != c.want.Tag {
+			t.Errorf("ParsePredictResult(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+	}
+}
+
+// TestVWDaemonStopProcessAliveRace exercises Stop and processAlive
+// concurrently against the same tracked process, guarding against a
+// regression of the data race on cmd/pid/exited.
+func TestVWDaemonStopProcessAliveRace(t *testing.T) {
+	cmd := exec.Command("sleep", "5")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	vw := &VWDaemon{cmd: cmd, pid: cmd.Process.Pid, exited: exited}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vw.processAlive()
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		vw.Stop()
+	}()
+
+	wg.Wait()
+}
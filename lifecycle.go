@@ -0,0 +1,78 @@
+package govw
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Closer is implemented by both VWDaemon and DaemonPool, so
+// RunWithSignals works whichever one an embedding program runs.
+type Closer interface {
+	Close() error
+}
+
+// Close tears down the daemon: it stops the vw process group (see Stop)
+// and closes every pooled TCP connection. The queue is drained even if
+// Stop fails, so a daemon whose process already died doesn't leak its
+// pooled connections. Safe to call even if Run was never called.
+func (vw *VWDaemon) Close() error {
+	stopErr := vw.Stop()
+
+	vw.drainTCPQueue()
+
+	return stopErr
+}
+
+// drainTCPQueue closes every pooled connection currently sitting in
+// TCPQueue. Connections borrowed by an in-flight Predict are not
+// touched; callers that need to guarantee none are in flight should
+// wait for them first (DaemonPool does this via its waitgroup).
+func (vw *VWDaemon) drainTCPQueue() {
+	queue := vw.queue()
+	for {
+		select {
+		case pc := <-queue:
+			pc.Close()
+		default:
+			return
+		}
+	}
+}
+
+// Close stops the model file checker goroutine and closes the
+// currently active daemon. It is idempotent and safe to call more than
+// once, and it blocks out any Reload already in flight so the
+// replacement daemon it starts gets closed too instead of leaking. Like
+// Reload, it waits for requests still in flight against the active
+// daemon to finish first, so a Predict call racing Close isn't left
+// blocked on a connection pool that's being torn down underneath it.
+func (p *DaemonPool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	p.closeOnce.Do(func() { close(p.done) })
+
+	entry := p.active.Load().(*poolEntry)
+	entry.inFlight.Wait()
+
+	return entry.daemon.Close()
+}
+
+// RunWithSignals installs handlers for SIGINT/SIGTERM and calls Close
+// on d when either is received or ctx is done, so embedding programs
+// get correct shutdown semantics for free.
+func RunWithSignals(ctx context.Context, d Closer) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case <-ctx.Done():
+	case <-sigCh:
+	}
+
+	return d.Close()
+}
@@ -0,0 +1,62 @@
+package govw
+
+import "log"
+
+// Logger is the structured logging interface VWDaemon reports through.
+// Satisfy it with logrus, zap, or any other logger to plug govw into a
+// larger service. The default, used when VWDaemon.Logger is nil, falls
+// back to the standard library's log package.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// stdLogger is the default Logger, backed by the standard library's
+// log package.
+type stdLogger struct{}
+
+func (stdLogger) Debugf(format string, args ...interface{}) { log.Printf("DEBUG "+format, args...) }
+func (stdLogger) Infof(format string, args ...interface{})  { log.Printf("INFO "+format, args...) }
+func (stdLogger) Warnf(format string, args ...interface{})  { log.Printf("WARN "+format, args...) }
+func (stdLogger) Errorf(format string, args ...interface{}) { log.Printf("ERROR "+format, args...) }
+
+func (vw *VWDaemon) logger() Logger {
+	if vw.Logger == nil {
+		return stdLogger{}
+	}
+
+	return vw.Logger
+}
+
+// Metrics is the set of counters and observations VWDaemon reports
+// through. Satisfy it with a Prometheus-backed implementation (or any
+// other metrics backend) to plug govw into a larger service. The
+// default, used when VWDaemon.Metrics is nil, discards everything.
+type Metrics interface {
+	IncPredictTotal()
+	IncPredictErrors()
+	ObservePredictLatency(seconds float64)
+	IncDaemonRestarts()
+	IncModelReloads()
+	SetTCPPoolSize(size int)
+}
+
+// noopMetrics is the default Metrics, which discards everything.
+type noopMetrics struct{}
+
+func (noopMetrics) IncPredictTotal()                      {}
+func (noopMetrics) IncPredictErrors()                     {}
+func (noopMetrics) ObservePredictLatency(seconds float64) {}
+func (noopMetrics) IncDaemonRestarts()                    {}
+func (noopMetrics) IncModelReloads()                      {}
+func (noopMetrics) SetTCPPoolSize(size int)               {}
+
+func (vw *VWDaemon) metrics() Metrics {
+	if vw.Metrics == nil {
+		return noopMetrics{}
+	}
+
+	return vw.Metrics
+}
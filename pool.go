@@ -0,0 +1,164 @@
+package govw
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// poolEntry pins one generation of a daemon together with a waitgroup
+// tracking predictions currently in flight against it.
+type poolEntry struct {
+	daemon   *VWDaemon
+	inFlight sync.WaitGroup
+}
+
+// DaemonPool holds the currently active VWDaemon behind an atomic
+// pointer, so Predict can read it lock-free while a model reload swaps
+// in a replacement daemon underneath, without dropping or racing
+// in-flight predictions.
+type DaemonPool struct {
+	active atomic.Value // *poolEntry
+	done   chan struct{}
+
+	// mu guards closed and serializes Reload's swap against Close, so a
+	// Reload racing a shutdown doesn't leak the replacement daemon it
+	// started.
+	mu        sync.Mutex
+	closed    bool
+	closeOnce sync.Once
+}
+
+// NewDaemonPool wraps an already-running daemon in a pool. If the
+// daemon's model is updatable, a goroutine is started to watch the
+// model file and hot-swap in a replacement daemon when it changes,
+// until the pool is closed.
+func NewDaemonPool(d *VWDaemon) *DaemonPool {
+	pool := &DaemonPool{done: make(chan struct{})}
+	pool.active.Store(&poolEntry{daemon: d})
+
+	if d.Model.Updatable {
+		go pool.modelFileChecker()
+	}
+
+	return pool
+}
+
+// Current returns the currently active daemon.
+func (p *DaemonPool) Current() *VWDaemon {
+	return p.active.Load().(*poolEntry).daemon
+}
+
+// Predict borrows the currently active daemon, keeping it pinned for the
+// duration of the call so a concurrent Reload won't stop it out from
+// under the request, then forwards to its Predict method.
+func (p *DaemonPool) Predict(pData []byte) (*Prediction, error) {
+	entry := p.pin()
+	defer entry.inFlight.Done()
+
+	return entry.daemon.Predict(pData)
+}
+
+// PredictContext borrows the currently active daemon exactly like
+// Predict, pinning it against a concurrent Reload for the duration of
+// the call, then forwards to its PredictContext method.
+func (p *DaemonPool) PredictContext(ctx context.Context, pData []byte) (*Prediction, error) {
+	entry := p.pin()
+	defer entry.inFlight.Done()
+
+	return entry.daemon.PredictContext(ctx, pData)
+}
+
+// PredictBatch borrows the currently active daemon exactly like
+// Predict, pinning it against a concurrent Reload for the duration of
+// the call, then forwards to its PredictBatch method. Callers that want
+// batching should use this instead of Current().PredictBatch, which
+// bypasses the pin and can race a Reload tearing the daemon down.
+func (p *DaemonPool) PredictBatch(examples [][]byte) ([]*Prediction, error) {
+	entry := p.pin()
+	defer entry.inFlight.Done()
+
+	return entry.daemon.PredictBatch(examples)
+}
+
+// pin increments the in-flight count of the currently active pool entry
+// and hands it back. A plain Load-then-Add races Reload: the Add could
+// land after Reload already observed the count hit zero and moved on to
+// Stop()-ing that entry's daemon. pin re-checks that the entry it just
+// incremented is still the active one; if Reload swapped in between,
+// it undoes the increment and retries against the new entry instead of
+// ever handing back one Reload has already (or is about to) tear down.
+func (p *DaemonPool) pin() *poolEntry {
+	for {
+		entry := p.active.Load().(*poolEntry)
+		entry.inFlight.Add(1)
+
+		if p.active.Load().(*poolEntry) == entry {
+			return entry
+		}
+
+		entry.inFlight.Done()
+	}
+}
+
+// Reload starts a replacement daemon on an alternate port, atomically
+// swaps it in as the active daemon, then waits for requests still in
+// flight on the old daemon to finish before stopping it.
+func (p *DaemonPool) Reload() error {
+	oldEntry := p.active.Load().(*poolEntry)
+	old := oldEntry.daemon
+
+	newVW, err := NewDaemon(old.BinPath, old.Port+1, old.Children, old.Model.Path, old.Test, old.Model.Updatable)
+	if err != nil {
+		return fmt.Errorf("daemon pool: failed to prepare replacement daemon: %w", err)
+	}
+	newVW.Logger = old.Logger
+	newVW.Metrics = old.Metrics
+
+	if err := newVW.Run(); err != nil {
+		return fmt.Errorf("daemon pool: failed to start replacement daemon: %w", err)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		newVW.Close()
+		return fmt.Errorf("daemon pool: closed during reload")
+	}
+	p.active.Store(&poolEntry{daemon: newVW})
+	p.mu.Unlock()
+
+	old.metrics().IncModelReloads()
+
+	oldEntry.inFlight.Wait()
+
+	return old.Stop()
+}
+
+// modelFileChecker watches the active daemon's model file and triggers
+// a Reload whenever it changes.
+func (p *DaemonPool) modelFileChecker() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-time.After(time.Second * 1): // TODO: Move count of second to config file
+		}
+
+		current := p.Current()
+
+		changed, err := current.Model.IsChanged()
+		if err != nil {
+			current.logger().Errorf("daemon pool: failed to check model file: %v", err)
+			continue
+		}
+
+		if changed {
+			if err := p.Reload(); err != nil {
+				current.logger().Errorf("daemon pool: model reload failed: %v", err)
+			}
+		}
+	}
+}
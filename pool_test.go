@@ -0,0 +1,107 @@
+package govw
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDaemonPoolPinAgainstConcurrentSwap(t *testing.T) {
+	pool := &DaemonPool{done: make(chan struct{})}
+	pool.active.Store(&poolEntry{daemon: &VWDaemon{}})
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			old := pool.active.Load().(*poolEntry)
+			pool.mu.Lock()
+			pool.active.Store(&poolEntry{daemon: &VWDaemon{}})
+			pool.mu.Unlock()
+
+			old.inFlight.Wait()
+		}
+	}()
+
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				entry := pool.pin()
+				entry.inFlight.Done()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(stop)
+}
+
+// TestDaemonPoolCloseWaitsForInFlight guards against a regression where
+// Close tore down the active daemon without waiting for requests
+// pinning it, leaving a concurrent Predict blocked on a connection pool
+// being drained out from under it.
+func TestDaemonPoolCloseWaitsForInFlight(t *testing.T) {
+	pool := &DaemonPool{done: make(chan struct{})}
+	entry := &poolEntry{daemon: &VWDaemon{}}
+	pool.active.Store(entry)
+
+	entry.inFlight.Add(1)
+
+	closeDone := make(chan error, 1)
+	go func() { closeDone <- pool.Close() }()
+
+	select {
+	case <-closeDone:
+		t.Fatal("Close returned before in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	entry.inFlight.Done()
+
+	select {
+	case err := <-closeDone:
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return after in-flight request finished")
+	}
+}
+
+// TestDaemonPoolPredictContextAndBatch guards against a regression where
+// DaemonPool only exposed the pin-protected Predict, forcing callers who
+// wanted PredictContext/PredictBatch to reach past the pin via
+// Current(), racing a concurrent Reload.
+func TestDaemonPoolPredictContextAndBatch(t *testing.T) {
+	vw := newFakeVWDaemon(t, 2)
+	pool := &DaemonPool{done: make(chan struct{})}
+	pool.active.Store(&poolEntry{daemon: vw})
+
+	prediction, err := pool.PredictContext(context.Background(), []byte("1 |f a"))
+	if err != nil {
+		t.Fatalf("PredictContext: %v", err)
+	}
+	if prediction.Value != 0.5 || prediction.Tag != "tag" {
+		t.Errorf("PredictContext got %+v, want {0.5 tag}", prediction)
+	}
+
+	predictions, err := pool.PredictBatch([][]byte{[]byte("1 |f a"), []byte("1 |f b")})
+	if err != nil {
+		t.Fatalf("PredictBatch: %v", err)
+	}
+	if len(predictions) != 2 {
+		t.Fatalf("got %d predictions, want 2", len(predictions))
+	}
+}
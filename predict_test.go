@@ -0,0 +1,112 @@
+package govw
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// startFakePredictServer emulates just enough of the VW daemon protocol
+// for PredictBatch/PredictStream tests: it replies with one prediction
+// line per non-blank line read, and ignores the blank line examples are
+// terminated with.
+func startFakePredictServer(t *testing.T) *net.TCPAddr {
+	t.Helper()
+
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("failed to start fake vw server: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(c net.Conn) {
+				defer c.Close()
+
+				reader := bufio.NewReader(c)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					if strings.TrimSpace(line) == "" {
+						continue
+					}
+
+					if _, err := fmt.Fprintf(c, "0.5 tag\n"); err != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr)
+}
+
+func newFakeVWDaemon(t *testing.T, size int) *VWDaemon {
+	t.Helper()
+
+	addr := startFakePredictServer(t)
+
+	vw := &VWDaemon{Port: addr.Port, TCPQueue: make(chan *pooledConn, size)}
+	for i := 0; i < size; i++ {
+		pc, err := vw.getTCPConn()
+		if err != nil {
+			t.Fatalf("failed to dial fake vw server: %v", err)
+		}
+		vw.TCPQueue <- pc
+	}
+
+	return vw
+}
+
+func TestPredictBatch(t *testing.T) {
+	vw := newFakeVWDaemon(t, 1)
+
+	predictions, err := vw.PredictBatch([][]byte{[]byte("1 |f a"), []byte("1 |f b"), []byte("1 |f c")})
+	if err != nil {
+		t.Fatalf("PredictBatch: %v", err)
+	}
+
+	if len(predictions) != 3 {
+		t.Fatalf("got %d predictions, want 3", len(predictions))
+	}
+	for _, p := range predictions {
+		if p.Value != 0.5 || p.Tag != "tag" {
+			t.Errorf("got %+v, want {0.5 tag}", p)
+		}
+	}
+}
+
+func TestPredictStreamBatches(t *testing.T) {
+	vw := newFakeVWDaemon(t, 1)
+
+	in := make(chan []byte)
+	out := make(chan *Prediction, predictStreamBatchSize)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		vw.PredictStream(in, out)
+	}()
+
+	for i := 0; i < predictStreamBatchSize; i++ {
+		in <- []byte(fmt.Sprintf("1 |f %d", i))
+	}
+	close(in)
+	<-done
+
+	if len(out) != predictStreamBatchSize {
+		t.Fatalf("got %d predictions, want %d", len(out), predictStreamBatchSize)
+	}
+}